@@ -0,0 +1,283 @@
+package crlchecker
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+)
+
+// reasonCodeRemoveFromCRL is the CRLReason value (RFC 5280 §5.3.1) a delta
+// CRL uses to indicate that a previously revoked (typically held)
+// certificate should be removed from the effective revoked set.
+const reasonCodeRemoveFromCRL = 8
+
+// oidExtensionDeltaCRLIndicator is the Delta CRL Indicator extension OID
+// (RFC 5280 §5.2.4), carrying the CRL Number of the base CRL a delta
+// applies to.
+var oidExtensionDeltaCRLIndicator = asn1.ObjectIdentifier{2, 5, 29, 27}
+
+// deltaBaseCRLNumber returns the Base CRL Number carried in crl's Delta CRL
+// Indicator extension, or nil if crl is not a delta CRL.
+func deltaBaseCRLNumber(crl *x509.RevocationList) *big.Int {
+	for _, ext := range crl.Extensions {
+		if !ext.Id.Equal(oidExtensionDeltaCRLIndicator) {
+			continue
+		}
+		var num *big.Int
+		if _, err := asn1.Unmarshal(ext.Value, &num); err != nil {
+			return nil
+		}
+		return num
+	}
+	return nil
+}
+
+// issuerKey identifies the issuer of a certificate or CRL for indexing and
+// lookup purposes, preferring the Authority Key Identifier (RFC 5280
+// §4.2.1.1) and falling back to the raw issuer distinguished name when no
+// AKID is present.
+func issuerKey(authorityKeyId, rawIssuer []byte) string {
+	if len(authorityKeyId) > 0 {
+		return "akid:" + hex.EncodeToString(authorityKeyId)
+	}
+	return "dn:" + string(rawIssuer)
+}
+
+// crlIssuerKey returns crl's issuerKey, comparable with a certificate's via
+// issuerKey(cert.AuthorityKeyId, cert.RawIssuer). x509.RevocationList's
+// AuthorityKeyId holds the raw, still-ASN.1-wrapped extension value rather
+// than the bare key identifier octets that x509.Certificate.AuthorityKeyId
+// holds, so it must be unwrapped before comparison.
+func crlIssuerKey(crl *x509.RevocationList) string {
+	return issuerKey(authorityKeyIdentifier(crl.AuthorityKeyId), crl.RawIssuer)
+}
+
+// authorityKeyIdentifier extracts the keyIdentifier octets from a raw
+// AuthorityKeyIdentifier extension value (RFC 5280 §4.2.1.1), or returns
+// raw unchanged if it can't be parsed as one.
+func authorityKeyIdentifier(raw []byte) []byte {
+	if len(raw) == 0 {
+		return raw
+	}
+	var akid struct {
+		Id []byte `asn1:"optional,tag:0"`
+	}
+	if _, err := asn1.Unmarshal(raw, &akid); err != nil {
+		return raw
+	}
+	return akid.Id
+}
+
+// crlSet holds, for one issuer, the base CRL plus the most recent delta CRL
+// that applies to it (RFC 5280 §5.2.4). delta is nil when no applicable
+// delta has been loaded.
+type crlSet struct {
+	base  *x509.RevocationList
+	delta *x509.RevocationList
+}
+
+// effectiveEntries returns the revoked-certificate entries in effect for
+// the set: the base CRL's entries, plus the delta's additions, minus any
+// entry the delta marks with reason code removeFromCRL.
+func (s *crlSet) effectiveEntries() []x509.RevocationListEntry {
+	if s.delta == nil {
+		return s.base.RevokedCertificateEntries
+	}
+
+	removed := make(map[string]bool)
+	for _, e := range s.delta.RevokedCertificateEntries {
+		if e.ReasonCode == reasonCodeRemoveFromCRL {
+			removed[e.SerialNumber.String()] = true
+		}
+	}
+
+	merged := make([]x509.RevocationListEntry, 0, len(s.base.RevokedCertificateEntries)+len(s.delta.RevokedCertificateEntries))
+	for _, e := range s.base.RevokedCertificateEntries {
+		if !removed[e.SerialNumber.String()] {
+			merged = append(merged, e)
+		}
+	}
+	for _, e := range s.delta.RevokedCertificateEntries {
+		if e.ReasonCode != reasonCodeRemoveFromCRL {
+			merged = append(merged, e)
+		}
+	}
+	return merged
+}
+
+// buildIssuerCRLSets groups crls by issuer, keeping the highest-numbered
+// base CRL per issuer and, if present, the most recent delta whose
+// BaseCRLNumber matches that base's CRLNumber. Deltas that target a base we
+// don't have, or that predate the base's ThisUpdate, are skipped.
+func buildIssuerCRLSets(crls []*x509.RevocationList) map[string]*crlSet {
+	bases := make(map[string]*x509.RevocationList)
+	var deltas []*x509.RevocationList
+
+	for _, crl := range crls {
+		if deltaBaseCRLNumber(crl) != nil {
+			deltas = append(deltas, crl)
+			continue
+		}
+		key := crlIssuerKey(crl)
+		if existing, ok := bases[key]; !ok || crlNumberGreater(crl.Number, existing.Number) {
+			bases[key] = crl
+		}
+	}
+
+	sets := make(map[string]*crlSet, len(bases))
+	for key, base := range bases {
+		sets[key] = &crlSet{base: base}
+	}
+
+	for _, delta := range deltas {
+		key := crlIssuerKey(delta)
+		set, ok := sets[key]
+		if !ok {
+			continue // no base CRL loaded for this issuer yet
+		}
+
+		baseNumber := deltaBaseCRLNumber(delta)
+		if set.base.Number == nil || baseNumber.Cmp(set.base.Number) != 0 {
+			continue // delta doesn't apply to the base we have (too old or too new)
+		}
+		if delta.ThisUpdate.Before(set.base.ThisUpdate) {
+			continue // stale delta, predates the base
+		}
+		if set.delta == nil || crlNumberGreater(delta.Number, set.delta.Number) {
+			set.delta = delta
+		}
+	}
+
+	return sets
+}
+
+func crlNumberGreater(a, b *big.Int) bool {
+	if a == nil {
+		return false
+	}
+	if b == nil {
+		return true
+	}
+	return a.Cmp(b) > 0
+}
+
+// recordCRLSetMetrics reports the entry count and NextUpdate of each
+// issuer's effective CRL view to recorder.
+func recordCRLSetMetrics(recorder Recorder, sets map[string]*crlSet) {
+	for _, set := range sets {
+		issuer := set.base.Issuer.String()
+		recorder.CRLEntries(issuer, len(set.effectiveEntries()))
+		recorder.CRLNextUpdate(issuer, set.base.NextUpdate)
+	}
+}
+
+// trustRoots holds CA certificates used to verify CRL signatures, indexed
+// the same way as CRLs so the matching issuer certificate can be found
+// quickly.
+type trustRoots struct {
+	byIssuer map[string]*x509.Certificate
+}
+
+// parseTrustRoots parses a PEM bundle of CA certificates into a trustRoots
+// index keyed by subject AKID/DN.
+func parseTrustRoots(pemBytes []byte) (*trustRoots, error) {
+	roots := &trustRoots{byIssuer: make(map[string]*x509.Certificate)}
+	for {
+		block, rest := pem.Decode(pemBytes)
+		if block == nil {
+			break
+		}
+		pemBytes = rest
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse trusted root certificate: %w", err)
+		}
+		roots.byIssuer[issuerKey(cert.SubjectKeyId, cert.RawSubject)] = cert
+	}
+	return roots, nil
+}
+
+// crlValidation bundles the settings providers need to accept or reject a
+// loaded CRL: the trust roots used to verify its signature, and how far
+// past NextUpdate a CRL may still be treated as valid.
+type crlValidation struct {
+	roots      *trustRoots
+	staleGrace time.Duration
+}
+
+// verifyCRL checks that crl has not expired (allowing staleGrace past
+// NextUpdate) and, if roots is non-nil, that it is signed by a matching
+// trusted root. A nil validation applies no signature check and no grace
+// period.
+func verifyCRL(crl *x509.RevocationList, validation *crlValidation, now time.Time) error {
+	var roots *trustRoots
+	var staleGrace time.Duration
+	if validation != nil {
+		roots = validation.roots
+		staleGrace = validation.staleGrace
+	}
+
+	if now.After(crl.NextUpdate.Add(staleGrace)) {
+		return fmt.Errorf("CRL for issuer %q expired at %s", crl.Issuer, crl.NextUpdate)
+	}
+	if now.Before(crl.ThisUpdate) {
+		return fmt.Errorf("CRL for issuer %q is not valid until %s", crl.Issuer, crl.ThisUpdate)
+	}
+
+	if roots == nil {
+		return nil
+	}
+
+	issuerCert, ok := roots.byIssuer[crlIssuerKey(crl)]
+	if !ok {
+		return fmt.Errorf("no trusted root matches CRL issuer %q", crl.Issuer)
+	}
+	if err := issuerCert.CheckSignature(crl.SignatureAlgorithm, crl.RawTBSRevocationList, crl.Signature); err != nil {
+		return fmt.Errorf("CRL signature verification failed for issuer %q: %w", crl.Issuer, err)
+	}
+	return nil
+}
+
+// lookupCRL returns the CRL covering cert's issuer from sets, verifying its
+// base CRL against validation and rejecting it if expired beyond the
+// configured staleness grace. If a delta CRL applies to the set, it is
+// verified the same way; a delta that fails validation (e.g. signed by a
+// key not in validation's trust roots) is ignored rather than trusted, and
+// lookup proceeds with the base CRL alone. Otherwise the returned
+// *x509.RevocationList is a shallow copy of the base with
+// RevokedCertificateEntries replaced by the merged base+delta view (RFC
+// 5280 §5.2.4), so callers can keep treating the result as a single CRL.
+func lookupCRL(sets map[string]*crlSet, cert *x509.Certificate, validation *crlValidation) (*x509.RevocationList, error) {
+	set, ok := sets[issuerKey(cert.AuthorityKeyId, cert.RawIssuer)]
+	if !ok {
+		return nil, fmt.Errorf("no CRL loaded for issuer %q", cert.Issuer)
+	}
+
+	if err := verifyCRL(set.base, validation, time.Now()); err != nil {
+		return nil, fmt.Errorf("no valid CRL for issuer %q: %w", cert.Issuer, err)
+	}
+
+	effective := *set
+	if effective.delta != nil {
+		if err := verifyCRL(effective.delta, validation, time.Now()); err != nil {
+			log.Printf("Ignoring delta CRL for issuer %q, failed validation: %v", cert.Issuer, err)
+			effective.delta = nil
+		}
+	}
+
+	if effective.delta == nil {
+		return set.base, nil
+	}
+
+	result := *set.base
+	result.RevokedCertificateEntries = effective.effectiveEntries()
+	return &result, nil
+}
@@ -0,0 +1,297 @@
+package crlchecker
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// deltaIndicatorExtension builds a Delta CRL Indicator extension (RFC 5280
+// §5.2.4) pointing at baseNumber.
+func deltaIndicatorExtension(t *testing.T, baseNumber *big.Int) pkix.Extension {
+	t.Helper()
+
+	value, err := asn1.Marshal(baseNumber)
+	if err != nil {
+		t.Fatalf("marshaling delta CRL indicator: %v", err)
+	}
+	return pkix.Extension{Id: oidExtensionDeltaCRLIndicator, Value: value}
+}
+
+func encodeCRLPEM(t *testing.T, der []byte) []byte {
+	t.Helper()
+	return pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+}
+
+// TestStaticCRLProviderDeltaRemovesThenReAddsSerial covers a delta CRL that
+// both lifts a hold (reason code removeFromCRL) and re-revokes the same
+// serial for a different reason in the same update, verifying the net
+// effect is that the certificate is still reported revoked.
+func TestStaticCRLProviderDeltaRemovesThenReAddsSerial(t *testing.T) {
+	ca := newTestCA(t, "delta-ca")
+	clientCert := ca.issueClientCert(t, "http://example.invalid/crl")
+
+	now := time.Now()
+	baseDER, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: now.Add(-time.Hour),
+		NextUpdate: now.Add(time.Hour),
+		RevokedCertificateEntries: []x509.RevocationListEntry{
+			{SerialNumber: clientCert.SerialNumber, RevocationTime: now.Add(-time.Hour), ReasonCode: 6 /* certificateHold */},
+		},
+	}, ca.cert, ca.key)
+	if err != nil {
+		t.Fatalf("creating base CRL: %v", err)
+	}
+
+	deltaDER, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(2),
+		ThisUpdate: now.Add(-time.Minute),
+		NextUpdate: now.Add(time.Hour),
+		ExtraExtensions: []pkix.Extension{
+			deltaIndicatorExtension(t, big.NewInt(1)),
+		},
+		RevokedCertificateEntries: []x509.RevocationListEntry{
+			{SerialNumber: clientCert.SerialNumber, RevocationTime: now.Add(-time.Minute + 1), ReasonCode: reasonCodeRemoveFromCRL},
+			{SerialNumber: clientCert.SerialNumber, RevocationTime: now.Add(-time.Minute + 2), ReasonCode: 1 /* keyCompromise */},
+		},
+	}, ca.cert, ca.key)
+	if err != nil {
+		t.Fatalf("creating delta CRL: %v", err)
+	}
+
+	bundle := append(encodeCRLPEM(t, baseDER), encodeCRLPEM(t, deltaDER)...)
+	provider, err := NewStaticCRLProvider(bundle, nil, nil)
+	if err != nil {
+		t.Fatalf("NewStaticCRLProvider: %v", err)
+	}
+
+	crl, err := provider.CRL(clientCert)
+	if err != nil {
+		t.Fatalf("provider.CRL: %v", err)
+	}
+
+	found := false
+	for _, e := range crl.RevokedCertificateEntries {
+		if e.SerialNumber.Cmp(clientCert.SerialNumber) == 0 {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("expected the serial removed then re-added in the same delta to still be reported revoked")
+	}
+}
+
+// TestStaticCRLProviderDeltaRemovesHeldSerial is the counterpart: a delta
+// that only lifts a hold, without re-adding the serial, must clear it.
+func TestStaticCRLProviderDeltaRemovesHeldSerial(t *testing.T) {
+	ca := newTestCA(t, "delta-ca-2")
+	clientCert := ca.issueClientCert(t, "http://example.invalid/crl")
+
+	now := time.Now()
+	baseDER, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: now.Add(-time.Hour),
+		NextUpdate: now.Add(time.Hour),
+		RevokedCertificateEntries: []x509.RevocationListEntry{
+			{SerialNumber: clientCert.SerialNumber, RevocationTime: now.Add(-time.Hour), ReasonCode: 6 /* certificateHold */},
+		},
+	}, ca.cert, ca.key)
+	if err != nil {
+		t.Fatalf("creating base CRL: %v", err)
+	}
+
+	deltaDER, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(2),
+		ThisUpdate: now.Add(-time.Minute),
+		NextUpdate: now.Add(time.Hour),
+		ExtraExtensions: []pkix.Extension{
+			deltaIndicatorExtension(t, big.NewInt(1)),
+		},
+		RevokedCertificateEntries: []x509.RevocationListEntry{
+			{SerialNumber: clientCert.SerialNumber, RevocationTime: now.Add(-time.Minute), ReasonCode: reasonCodeRemoveFromCRL},
+		},
+	}, ca.cert, ca.key)
+	if err != nil {
+		t.Fatalf("creating delta CRL: %v", err)
+	}
+
+	bundle := append(encodeCRLPEM(t, baseDER), encodeCRLPEM(t, deltaDER)...)
+	provider, err := NewStaticCRLProvider(bundle, nil, nil)
+	if err != nil {
+		t.Fatalf("NewStaticCRLProvider: %v", err)
+	}
+
+	crl, err := provider.CRL(clientCert)
+	if err != nil {
+		t.Fatalf("provider.CRL: %v", err)
+	}
+
+	for _, e := range crl.RevokedCertificateEntries {
+		if e.SerialNumber.Cmp(clientCert.SerialNumber) == 0 {
+			t.Fatal("expected the held serial lifted by the delta to no longer be reported revoked")
+		}
+	}
+}
+
+// trustRootsFor builds a trustRoots index containing only ca's certificate.
+func trustRootsFor(t *testing.T, ca *testCA) *trustRoots {
+	t.Helper()
+	roots, err := parseTrustRoots([]byte(certPEM(t, ca.cert)))
+	if err != nil {
+		t.Fatalf("parseTrustRoots: %v", err)
+	}
+	return roots
+}
+
+// TestVerifyCRLTrustedSignatureAccepted covers the positive case of
+// verifyCRL's trust-root check: a CRL signed by a certificate present in
+// validation.roots is accepted.
+func TestVerifyCRLTrustedSignatureAccepted(t *testing.T) {
+	ca := newTestCA(t, "verify-ca")
+	now := time.Now()
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: now.Add(-time.Hour),
+		NextUpdate: now.Add(time.Hour),
+	}, ca.cert, ca.key)
+	if err != nil {
+		t.Fatalf("creating CRL: %v", err)
+	}
+	crl, err := x509.ParseRevocationList(der)
+	if err != nil {
+		t.Fatalf("parsing CRL: %v", err)
+	}
+
+	validation := &crlValidation{roots: trustRootsFor(t, ca)}
+	if err := verifyCRL(crl, validation, now); err != nil {
+		t.Fatalf("expected a correctly-signed, trusted CRL to be accepted, got: %v", err)
+	}
+}
+
+// TestVerifyCRLUntrustedSignatureRejected is the negative counterpart: a CRL
+// claiming the trusted issuer's identity (same DN/AKID) but signed with a
+// different key must be rejected.
+func TestVerifyCRLUntrustedSignatureRejected(t *testing.T) {
+	ca := newTestCA(t, "verify-ca-2")
+	impostorKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating impostor key: %v", err)
+	}
+
+	now := time.Now()
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: now.Add(-time.Hour),
+		NextUpdate: now.Add(time.Hour),
+	}, ca.cert, impostorKey)
+	if err != nil {
+		t.Fatalf("creating CRL: %v", err)
+	}
+	crl, err := x509.ParseRevocationList(der)
+	if err != nil {
+		t.Fatalf("parsing CRL: %v", err)
+	}
+
+	validation := &crlValidation{roots: trustRootsFor(t, ca)}
+	if err := verifyCRL(crl, validation, now); err == nil {
+		t.Fatal("expected a CRL signed by an untrusted key to be rejected")
+	}
+}
+
+// TestVerifyCRLStaleGraceExpiry covers staleGrace: a CRL just past
+// NextUpdate is rejected with no grace, but accepted once staleGrace covers
+// the gap.
+func TestVerifyCRLStaleGraceExpiry(t *testing.T) {
+	ca := newTestCA(t, "verify-ca-3")
+	now := time.Now()
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: now.Add(-2 * time.Hour),
+		NextUpdate: now.Add(-time.Minute),
+	}, ca.cert, ca.key)
+	if err != nil {
+		t.Fatalf("creating CRL: %v", err)
+	}
+	crl, err := x509.ParseRevocationList(der)
+	if err != nil {
+		t.Fatalf("parsing CRL: %v", err)
+	}
+
+	if err := verifyCRL(crl, &crlValidation{staleGrace: 0}, now); err == nil {
+		t.Fatal("expected a CRL past NextUpdate to be rejected with no staleGrace")
+	}
+	if err := verifyCRL(crl, &crlValidation{staleGrace: time.Hour}, now); err != nil {
+		t.Fatalf("expected staleGrace to cover the expiry gap, got: %v", err)
+	}
+}
+
+// TestStaticCRLProviderIgnoresDeltaSignedByUntrustedKey reproduces the bug
+// where lookupCRL merged a delta CRL's entries in without ever verifying
+// the delta itself: a delta claiming the trusted issuer's identity but
+// signed by a different key, carrying a removeFromCRL entry for a
+// genuinely-revoked serial, must not un-revoke that certificate.
+func TestStaticCRLProviderIgnoresDeltaSignedByUntrustedKey(t *testing.T) {
+	ca := newTestCA(t, "delta-untrusted-ca")
+	clientCert := ca.issueClientCert(t, "http://example.invalid/crl")
+	impostorKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating impostor key: %v", err)
+	}
+
+	now := time.Now()
+	baseDER, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: now.Add(-time.Hour),
+		NextUpdate: now.Add(time.Hour),
+		RevokedCertificateEntries: []x509.RevocationListEntry{
+			{SerialNumber: clientCert.SerialNumber, RevocationTime: now.Add(-time.Hour), ReasonCode: 1 /* keyCompromise */},
+		},
+	}, ca.cert, ca.key)
+	if err != nil {
+		t.Fatalf("creating base CRL: %v", err)
+	}
+
+	// Signed with impostorKey, not ca.key: same claimed issuer, forged
+	// signature.
+	deltaDER, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(2),
+		ThisUpdate: now.Add(-time.Minute),
+		NextUpdate: now.Add(time.Hour),
+		ExtraExtensions: []pkix.Extension{
+			deltaIndicatorExtension(t, big.NewInt(1)),
+		},
+		RevokedCertificateEntries: []x509.RevocationListEntry{
+			{SerialNumber: clientCert.SerialNumber, RevocationTime: now.Add(-time.Minute), ReasonCode: reasonCodeRemoveFromCRL},
+		},
+	}, ca.cert, impostorKey)
+	if err != nil {
+		t.Fatalf("creating delta CRL: %v", err)
+	}
+
+	bundle := append(encodeCRLPEM(t, baseDER), encodeCRLPEM(t, deltaDER)...)
+	validation := &crlValidation{roots: trustRootsFor(t, ca)}
+	provider, err := NewStaticCRLProvider(bundle, validation, nil)
+	if err != nil {
+		t.Fatalf("NewStaticCRLProvider: %v", err)
+	}
+
+	crl, err := provider.CRL(clientCert)
+	if err != nil {
+		t.Fatalf("provider.CRL: %v", err)
+	}
+
+	for _, e := range crl.RevokedCertificateEntries {
+		if e.SerialNumber.Cmp(clientCert.SerialNumber) == 0 {
+			return
+		}
+	}
+	t.Fatal("expected the forged delta's removeFromCRL entry to be ignored, leaving the certificate revoked")
+}
@@ -0,0 +1,69 @@
+package crlchecker
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+)
+
+// Enforcement modes for Config.EnforcementMode, governing what happens when
+// a client certificate's revocation status can't be determined (no CRL
+// loaded for its issuer, or the CRL is stale beyond its staleness grace).
+const (
+	// EnforcementPermissive forwards the request, logging that the check
+	// was skipped. This is the default, matching the plugin's original
+	// fail-open behavior.
+	EnforcementPermissive = "permissive"
+	// EnforcementStrict denies the request with a 401.
+	EnforcementStrict = "strict"
+	// EnforcementWarn forwards the request but logs a warning, for
+	// operators rolling out strict enforcement who want visibility first.
+	EnforcementWarn = "warn"
+)
+
+// trustedIssuers restricts accepted client certificates to a configured
+// allow-list of issuers, checked before any CRL or OCSP lookup.
+type trustedIssuers struct {
+	// certs holds the CA certificates supplied as PEM, indexed by
+	// issuerKey(SubjectKeyId, RawSubject) so the candidate issuer for a
+	// client cert can be found before verifying the signature against it.
+	certs map[string]*x509.Certificate
+	// dns holds issuer subject DNs supplied as literal strings, matched
+	// against the client cert's self-reported Issuer.String() since no CA
+	// certificate is available to verify a DN entry against.
+	dns map[string]bool
+}
+
+// parseTrustedIssuers builds a trustedIssuers allow-list from entries, each
+// either a PEM-encoded CA certificate or a literal issuer subject DN (as
+// rendered by pkix.Name.String()). Prefer PEM entries: they're checked
+// against the certificate's actual signature, not just its claimed issuer.
+func parseTrustedIssuers(entries []string) (*trustedIssuers, error) {
+	t := &trustedIssuers{certs: make(map[string]*x509.Certificate), dns: make(map[string]bool)}
+	for _, entry := range entries {
+		block, _ := pem.Decode([]byte(entry))
+		if block == nil || block.Type != "CERTIFICATE" {
+			t.dns[entry] = true
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse trusted issuer certificate: %w", err)
+		}
+		t.certs[issuerKey(cert.SubjectKeyId, cert.RawSubject)] = cert
+	}
+	return t, nil
+}
+
+// allows reports whether cert was actually issued by one of t's configured
+// CA certificates. Falls back to comparing cert's self-reported issuer DN
+// against t's literal DN entries, which trusts the claimed issuer as-is
+// since there's no certificate to verify a signature against.
+func (t *trustedIssuers) allows(cert *x509.Certificate) bool {
+	if issuer, ok := t.certs[issuerKey(cert.AuthorityKeyId, cert.RawIssuer)]; ok {
+		if err := cert.CheckSignatureFrom(issuer); err == nil {
+			return true
+		}
+	}
+	return t.dns[cert.Issuer.String()]
+}
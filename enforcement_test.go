@@ -0,0 +1,41 @@
+package crlchecker
+
+import "testing"
+
+// TestTrustedIssuersRequiresActualSignature guards against a certificate
+// merely claiming to be issued by a trusted CA (matching AKID/issuer DN)
+// without actually being signed by it.
+func TestTrustedIssuersRequiresActualSignature(t *testing.T) {
+	trustedCA := newTestCA(t, "trusted-ca")
+	impostorCA := newTestCA(t, "trusted-ca") // same CN/SubjectKeyId as trustedCA
+
+	trusted, err := parseTrustedIssuers([]string{certPEM(t, trustedCA.cert)})
+	if err != nil {
+		t.Fatalf("parseTrustedIssuers: %v", err)
+	}
+
+	genuine := trustedCA.issueClientCert(t, "http://example.invalid/crl")
+	if !trusted.allows(genuine) {
+		t.Fatal("expected a certificate actually signed by the trusted CA to be allowed")
+	}
+
+	forged := impostorCA.issueClientCert(t, "http://example.invalid/crl")
+	if trusted.allows(forged) {
+		t.Fatal("expected a certificate only claiming the trusted issuer's identity to be rejected")
+	}
+}
+
+// TestTrustedIssuersDNFallback covers the literal-DN entry path, which has
+// no certificate to verify a signature against.
+func TestTrustedIssuersDNFallback(t *testing.T) {
+	ca := newTestCA(t, "dn-ca")
+	cert := ca.issueClientCert(t, "http://example.invalid/crl")
+
+	trusted, err := parseTrustedIssuers([]string{ca.cert.Issuer.String()})
+	if err != nil {
+		t.Fatalf("parseTrustedIssuers: %v", err)
+	}
+	if !trusted.allows(cert) {
+		t.Fatal("expected a matching literal issuer DN to be allowed")
+	}
+}
@@ -3,21 +3,85 @@ package crlchecker
 import (
 	"context"
 	"crypto/x509"
-	"encoding/pem"
+	"encoding/json"
 	"fmt"
 	"log"
+	"math/big"
 	"net/http"
-	"os"
-	"slices"
 	"strings"
-	"sync/atomic"
 	"time"
+
+	"golang.org/x/crypto/ocsp"
 )
 
 const DefaultCRLPath = "/pki/crl/crl.pem"
 
 type Config struct {
+	// CRLFilePath is either a single PEM bundle file or a directory
+	// containing *.crl/*.pem files, all of which are loaded and watched
+	// for changes.
 	CRLFilePath string `json:"crlFilePath"`
+
+	// CRLPollFallback, when true, reloads CRLFilePath on a 1-minute
+	// polling interval instead of using fsnotify. Use this on filesystems
+	// where inotify-style events are unreliable, such as NFS mounts or
+	// some container overlay filesystems.
+	CRLPollFallback bool `json:"crlPollFallback"`
+
+	// StaticCRLPEM, if set, is parsed as one or more concatenated
+	// PEM-encoded CRLs and consulted in addition to CRLFilePath. Useful
+	// for embedding a CRL directly in the plugin configuration.
+	StaticCRLPEM string `json:"staticCRLPEM"`
+
+	// EnableHTTPFetch, when true, falls back to fetching a CRL from the
+	// client certificate's CRL Distribution Point when no other
+	// configured provider has one for the cert's issuer.
+	EnableHTTPFetch bool `json:"enableHTTPFetch"`
+
+	// HTTPCacheSize bounds how many issuers' CRLs the HTTP provider keeps
+	// cached in memory. Defaults to DefaultHTTPCacheSize.
+	HTTPCacheSize int `json:"httpCacheSize"`
+
+	// TrustedRootsPEM, if set, is a bundle of concatenated PEM-encoded CA
+	// certificates used to verify CRL signatures. Without it, loaded CRLs
+	// are trusted as-is beyond their ThisUpdate/NextUpdate validity window.
+	TrustedRootsPEM string `json:"trustedRootsPEM"`
+
+	// EnableOCSP, when true, checks the stapled OCSP response (if any) and
+	// then the client certificate's AIA OCSP responder before falling
+	// back to the CRL check.
+	EnableOCSP bool `json:"enableOCSP"`
+
+	// OCSPHardFail, when true, denies the request if the OCSP responder
+	// cannot be reached instead of falling back to the CRL check.
+	OCSPHardFail bool `json:"ocspHardFail"`
+
+	// EnforcementMode controls what happens when a client certificate's
+	// revocation status can't be determined: EnforcementStrict denies the
+	// request, EnforcementWarn forwards it with a warning logged, and
+	// EnforcementPermissive (the default) forwards it, logging that the
+	// check was skipped, matching the plugin's original fail-open behavior.
+	EnforcementMode string `json:"enforcementMode"`
+
+	// TrustedIssuers, if set, restricts accepted client certificates to
+	// those whose issuer matches an entry in the list, checked before any
+	// CRL or OCSP lookup; a certificate from an unlisted issuer is
+	// rejected with a 401. Each entry is either a PEM-encoded CA
+	// certificate or a literal issuer subject DN (as rendered by
+	// pkix.Name.String()).
+	TrustedIssuers []string `json:"trustedIssuers"`
+
+	// CRLStaleGrace extends how long a CRL remains usable past its
+	// NextUpdate, as a Go duration string (e.g. "15m"). A CRL older than
+	// NextUpdate+CRLStaleGrace is treated the same as no CRL being loaded
+	// for that issuer, subject to EnforcementMode.
+	CRLStaleGrace string `json:"crlStaleGrace"`
+
+	// Recorder receives metrics about CRL reloads and revocation checks.
+	// It can't be set through the plugin's static JSON configuration; Go
+	// callers embedding CRLChecker as a library should set it on the
+	// Config passed to New. A nil Recorder discards all metrics.
+	Recorder Recorder `json:"-"`
 }
 
 func CreateConfig() *Config {
@@ -26,16 +90,15 @@ func CreateConfig() *Config {
 	}
 }
 
-type CRLData struct {
-	revokedSerials []string
-	modTime        time.Time
-}
-
 type CRLChecker struct {
-	next    http.Handler
-	name    string
-	config  *Config
-	crlData atomic.Value
+	next            http.Handler
+	name            string
+	config          *Config
+	provider        CRLProvider
+	ocsp            *OCSPChecker
+	recorder        Recorder
+	enforcementMode string
+	trustedIssuers  *trustedIssuers
 }
 
 func New(ctx context.Context, next http.Handler, config *Config, name string) (http.Handler, error) {
@@ -44,131 +107,243 @@ func New(ctx context.Context, next http.Handler, config *Config, name string) (h
 	}
 	log.Printf("Starting TLS CRL Checker plugin %q with config: %+v\n", name, config)
 
-	tc := &CRLChecker{
-		next:   next,
-		name:   name,
-		config: config,
+	recorder := config.Recorder
+	if recorder == nil {
+		recorder = noopRecorder{}
 	}
 
-	tc.loadCRL()
+	enforcementMode := config.EnforcementMode
+	if enforcementMode == "" {
+		enforcementMode = EnforcementPermissive
+	}
+	if enforcementMode != EnforcementPermissive && enforcementMode != EnforcementStrict && enforcementMode != EnforcementWarn {
+		return nil, fmt.Errorf("invalid enforcementMode %q", config.EnforcementMode)
+	}
 
-	go tc.watchCRLFile()
+	var trusted *trustedIssuers
+	if len(config.TrustedIssuers) > 0 {
+		var err error
+		trusted, err = parseTrustedIssuers(config.TrustedIssuers)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse trustedIssuers: %w", err)
+		}
+	}
 
-	return tc, nil
-}
+	var staleGrace time.Duration
+	if config.CRLStaleGrace != "" {
+		var err error
+		staleGrace, err = time.ParseDuration(config.CRLStaleGrace)
+		if err != nil {
+			return nil, fmt.Errorf("invalid crlStaleGrace: %w", err)
+		}
+	}
 
-func (tc *CRLChecker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
-		http.Error(w, "TLS client certificate is required for authentication.", http.StatusUnauthorized)
-		return
+	var roots *trustRoots
+	if config.TrustedRootsPEM != "" {
+		var err error
+		roots, err = parseTrustRoots([]byte(config.TrustedRootsPEM))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse trustedRootsPEM: %w", err)
+		}
 	}
+	validation := &crlValidation{roots: roots, staleGrace: staleGrace}
 
-	clientCert := r.TLS.PeerCertificates[0]
+	var providers []CRLProvider
 
-	crlDataInterface := tc.crlData.Load()
-	if crlDataInterface == nil {
-		log.Println("CRL data is not available. Proceeding without CRL checks.")
-		tc.next.ServeHTTP(w, r)
-		return
+	fileProvider, err := NewFileCRLProvider(config.CRLFilePath, validation, config.CRLPollFallback, recorder)
+	if err != nil {
+		log.Printf("Failed to start file CRL provider: %v", err)
+	} else {
+		providers = append(providers, fileProvider)
 	}
-	crlData := crlDataInterface.(*CRLData)
-
-	serialStr := clientCert.SerialNumber.String()
-	if slices.Contains(crlData.revokedSerials, serialStr) {
-		serialHex := fmt.Sprintf("%X", clientCert.SerialNumber)
-		var serialParts []string
-		for i := 0; i < len(serialHex); i += 2 {
-			end := i + 2
-			if end > len(serialHex) {
-				end = len(serialHex)
-			}
-			serialParts = append(serialParts, serialHex[i:end])
-		}
-		serialFormatted := strings.Join(serialParts, ":")
 
-		commonName := clientCert.Subject.CommonName
-
-		sans := getCertificateSANs(clientCert)
+	if config.StaticCRLPEM != "" {
+		staticProvider, err := NewStaticCRLProvider([]byte(config.StaticCRLPEM), validation, recorder)
+		if err != nil {
+			closeProviders(providers)
+			return nil, fmt.Errorf("failed to parse staticCRLPEM: %w", err)
+		}
+		providers = append(providers, staticProvider)
+	}
 
-		log.Printf("Revoked certificate detected: CN=%s, SANs=%s, Serial Number: %s\n", commonName, sans, serialFormatted)
+	if config.EnableHTTPFetch {
+		providers = append(providers, NewHTTPCRLProvider(nil, config.HTTPCacheSize, validation, recorder))
+	}
 
-		http.Error(w, "Certificate is revoked.", http.StatusUnauthorized)
-		return
+	var ocspChecker *OCSPChecker
+	if config.EnableOCSP {
+		ocspChecker = NewOCSPChecker(config.OCSPHardFail)
 	}
 
-	tc.next.ServeHTTP(w, r)
+	return &CRLChecker{
+		next:            next,
+		name:            name,
+		config:          config,
+		provider:        NewMultiProvider(providers...),
+		ocsp:            ocspChecker,
+		recorder:        recorder,
+		enforcementMode: enforcementMode,
+		trustedIssuers:  trusted,
+	}, nil
 }
 
-func getCertificateSANs(cert *x509.Certificate) string {
-	var sans []string
-
-	for _, email := range cert.EmailAddresses {
-		sans = append(sans, fmt.Sprintf("Email:%s", email))
+// closeProviders closes every provider already constructed, used to avoid
+// leaking a provider's background goroutine (e.g. FileCRLProvider's
+// fsnotify watcher) when New fails after some providers have started.
+func closeProviders(providers []CRLProvider) {
+	for _, p := range providers {
+		if err := p.Close(); err != nil {
+			log.Printf("Error closing CRL provider during startup failure: %v", err)
+		}
 	}
-
-	return strings.Join(sans, ", ")
 }
 
-func (tc *CRLChecker) loadCRL() {
-	crlBytes, err := os.ReadFile(tc.config.CRLFilePath)
-	if err != nil {
-		log.Printf("Failed to read CRL file at %s: %v", tc.config.CRLFilePath, err)
+func (tc *CRLChecker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		http.Error(w, "TLS client certificate is required for authentication.", http.StatusUnauthorized)
 		return
 	}
 
-	var revokedSerials []string
+	clientCert := r.TLS.PeerCertificates[0]
 
-	//iterate through all CRLs in concatenated CRL file and get the revoked serials
-	for {
-		block, rest := pem.Decode(crlBytes)
-		if block == nil {
-			break
+	if tc.trustedIssuers != nil && !tc.trustedIssuers.allows(clientCert) {
+		tc.recorder.CRLCheck("denied")
+		log.Printf("Rejecting client certificate from untrusted issuer %q", clientCert.Issuer)
+		http.Error(w, "Certificate issuer is not trusted.", http.StatusUnauthorized)
+		return
+	}
+
+	if tc.ocsp != nil {
+		var issuer *x509.Certificate
+		if len(r.TLS.PeerCertificates) > 1 {
+			issuer = r.TLS.PeerCertificates[1]
 		}
-		parsedCRL, err := x509.ParseRevocationList(block.Bytes)
-		if err != nil {
-			log.Printf("Failed to parse CRL file at %s: %v", tc.config.CRLFilePath, err)
+
+		status, err := tc.ocsp.Check(clientCert, issuer, r.TLS.OCSPResponse)
+		switch {
+		case err != nil:
+			// OCSPHardFail is set: the status couldn't be determined, so
+			// deny rather than falling back to the CRL check.
+			tc.recorder.CRLCheck("denied")
+			log.Printf("Denying request: OCSP check failed for %q with hard-fail enabled: %v", clientCert.Subject.CommonName, err)
+			http.Error(w, "Revocation status is unavailable.", http.StatusUnauthorized)
+			return
+		case status == ocsp.Good:
+			tc.recorder.CRLCheck("allowed")
+			tc.next.ServeHTTP(w, r)
+			return
+		case status == ocsp.Revoked:
+			tc.denyRevoked(w, clientCert, "ocsp")
 			return
 		}
-		crlBytes = rest
-		for _, rc := range parsedCRL.RevokedCertificateEntries {
-			revokedSerials = append(revokedSerials, rc.SerialNumber.String())
-		}
+		// status == ocsp.Unknown: fall through to the CRL check.
 	}
 
-	info, err := os.Stat(tc.config.CRLFilePath)
+	crl, err := tc.provider.CRL(clientCert)
 	if err != nil {
-		log.Printf("Failed to stat CRL file at %s: %v", tc.config.CRLFilePath, err)
+		tc.handleUnavailable(w, r, clientCert, err)
 		return
 	}
 
-	newCRLData := &CRLData{
-		revokedSerials: revokedSerials,
-		modTime:        info.ModTime(),
+	for _, rc := range crl.RevokedCertificateEntries {
+		if rc.SerialNumber.Cmp(clientCert.SerialNumber) == 0 {
+			tc.denyRevoked(w, clientCert, "crl")
+			return
+		}
 	}
 
-	tc.crlData.Store(newCRLData)
-	log.Println("CRL file loaded successfully.")
+	tc.recorder.CRLCheck("allowed")
+	tc.next.ServeHTTP(w, r)
 }
 
-func (tc *CRLChecker) watchCRLFile() {
-	ticker := time.NewTicker(1 * time.Minute)
-	defer ticker.Stop()
+// handleUnavailable decides, based on EnforcementMode, what to do when no
+// valid CRL could be obtained for cert's issuer: deny (strict), forward
+// with a warning logged (warn), or forward with a note that CRL checks were
+// skipped (permissive, the original fail-open behavior).
+func (tc *CRLChecker) handleUnavailable(w http.ResponseWriter, r *http.Request, cert *x509.Certificate, err error) {
+	tc.recorder.CRLCheck("unavailable")
 
-	for range ticker.C {
-		info, err := os.Stat(tc.config.CRLFilePath)
-		if err != nil {
-			log.Printf("Error accessing CRL file: %v\n", err)
-			continue
-		}
+	if tc.enforcementMode == EnforcementStrict {
+		log.Printf("Denying request: revocation status unavailable for issuer %q: %v", cert.Issuer, err)
+		http.Error(w, "Revocation status is unavailable.", http.StatusUnauthorized)
+		return
+	}
 
-		crlDataInterface := tc.crlData.Load()
-		var lastModTime time.Time
-		if crlDataInterface != nil {
-			lastModTime = crlDataInterface.(*CRLData).modTime
-		}
+	if tc.enforcementMode == EnforcementWarn {
+		log.Printf("WARNING: proceeding without a revocation check for issuer %q: %v", cert.Issuer, err)
+	} else {
+		log.Printf("CRL data is not available for issuer %q: %v. Proceeding without CRL checks.", cert.Issuer, err)
+	}
+	tc.next.ServeHTTP(w, r)
+}
 
-		if info.ModTime().After(lastModTime) {
-			tc.loadCRL()
+// revocationAuditEvent is the structured denial event logged for SIEM
+// consumption, one JSON object per line.
+type revocationAuditEvent struct {
+	Event  string   `json:"event"`
+	CN     string   `json:"cn"`
+	SANs   []string `json:"sans,omitempty"`
+	Serial string   `json:"serial"`
+	Issuer string   `json:"issuer"`
+	Source string   `json:"source"`
+}
+
+// denyRevoked records the denial, logs a structured audit event, and
+// rejects the request with a 401.
+func (tc *CRLChecker) denyRevoked(w http.ResponseWriter, cert *x509.Certificate, source string) {
+	tc.recorder.CRLCheck("denied")
+	tc.recorder.RevokedDenied(cert.Issuer.String())
+
+	event := revocationAuditEvent{
+		Event:  "certificate_revoked",
+		CN:     cert.Subject.CommonName,
+		SANs:   getCertificateSANs(cert),
+		Serial: formatSerial(cert.SerialNumber),
+		Issuer: cert.Issuer.String(),
+		Source: source,
+	}
+	if b, err := json.Marshal(event); err == nil {
+		log.Println(string(b))
+	} else {
+		log.Printf("Revoked certificate detected via %s: CN=%s, Serial Number: %s (failed to marshal audit event: %v)",
+			source, cert.Subject.CommonName, event.Serial, err)
+	}
+
+	http.Error(w, "Certificate is revoked.", http.StatusUnauthorized)
+}
+
+// formatSerial renders serial as colon-separated hex octets, e.g. "01:A2:3F".
+func formatSerial(serial *big.Int) string {
+	serialHex := fmt.Sprintf("%X", serial)
+	if len(serialHex)%2 == 1 {
+		serialHex = "0" + serialHex
+	}
+	var parts []string
+	for i := 0; i < len(serialHex); i += 2 {
+		end := i + 2
+		if end > len(serialHex) {
+			end = len(serialHex)
 		}
+		parts = append(parts, serialHex[i:end])
 	}
+	return strings.Join(parts, ":")
+}
+
+func getCertificateSANs(cert *x509.Certificate) []string {
+	var sans []string
+
+	for _, email := range cert.EmailAddresses {
+		sans = append(sans, fmt.Sprintf("Email:%s", email))
+	}
+	for _, dns := range cert.DNSNames {
+		sans = append(sans, fmt.Sprintf("DNS:%s", dns))
+	}
+	for _, uri := range cert.URIs {
+		sans = append(sans, fmt.Sprintf("URI:%s", uri))
+	}
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, fmt.Sprintf("IP:%s", ip))
+	}
+
+	return sans
 }
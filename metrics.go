@@ -0,0 +1,33 @@
+package crlchecker
+
+import "time"
+
+// Recorder receives structured metrics about CRLChecker's operation.
+// Implementations must be safe for concurrent use. A nil Config.Recorder
+// falls back to noopRecorder, so metrics remain entirely opt-in.
+type Recorder interface {
+	// CRLReload records the outcome of an attempt to (re)load a CRL
+	// source; result is "success" or "error".
+	CRLReload(result string)
+	// CRLCheck records the outcome of a revocation check; result is
+	// "allowed", "denied", or "unavailable".
+	CRLCheck(result string)
+	// RevokedDenied records a request denied because the client
+	// certificate was found revoked, labeled by issuer DN.
+	RevokedDenied(issuer string)
+	// CRLEntries records the number of revoked-certificate entries
+	// currently loaded for issuer.
+	CRLEntries(issuer string, count int)
+	// CRLNextUpdate records the NextUpdate time of the loaded CRL for
+	// issuer, so operators can alert on CRLs approaching expiry.
+	CRLNextUpdate(issuer string, nextUpdate time.Time)
+}
+
+// noopRecorder discards all metrics.
+type noopRecorder struct{}
+
+func (noopRecorder) CRLReload(string)                {}
+func (noopRecorder) CRLCheck(string)                 {}
+func (noopRecorder) RevokedDenied(string)            {}
+func (noopRecorder) CRLEntries(string, int)          {}
+func (noopRecorder) CRLNextUpdate(string, time.Time) {}
@@ -0,0 +1,64 @@
+package crlchecker
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PrometheusRecorder implements Recorder with client_golang metrics:
+// counters crl_reload_total{result}, crl_check_total{result}, and
+// crl_revoked_denied_total{issuer}; gauges crl_entries{issuer} and
+// crl_next_update_seconds{issuer}.
+type PrometheusRecorder struct {
+	reloadTotal *prometheus.CounterVec
+	checkTotal  *prometheus.CounterVec
+	deniedTotal *prometheus.CounterVec
+	entries     *prometheus.GaugeVec
+	nextUpdate  *prometheus.GaugeVec
+}
+
+// NewPrometheusRecorder creates a PrometheusRecorder and registers its
+// metrics with reg.
+func NewPrometheusRecorder(reg prometheus.Registerer) *PrometheusRecorder {
+	r := &PrometheusRecorder{
+		reloadTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "crl_reload_total",
+			Help: "Total number of CRL (re)load attempts, by result.",
+		}, []string{"result"}),
+		checkTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "crl_check_total",
+			Help: "Total number of revocation checks performed, by result.",
+		}, []string{"result"}),
+		deniedTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "crl_revoked_denied_total",
+			Help: "Total number of requests denied due to a revoked certificate, by issuer.",
+		}, []string{"issuer"}),
+		entries: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "crl_entries",
+			Help: "Number of revoked-certificate entries currently loaded, by issuer.",
+		}, []string{"issuer"}),
+		nextUpdate: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "crl_next_update_seconds",
+			Help: "Unix timestamp of the loaded CRL's NextUpdate, by issuer.",
+		}, []string{"issuer"}),
+	}
+	reg.MustRegister(r.reloadTotal, r.checkTotal, r.deniedTotal, r.entries, r.nextUpdate)
+	return r
+}
+
+func (r *PrometheusRecorder) CRLReload(result string) { r.reloadTotal.WithLabelValues(result).Inc() }
+
+func (r *PrometheusRecorder) CRLCheck(result string) { r.checkTotal.WithLabelValues(result).Inc() }
+
+func (r *PrometheusRecorder) RevokedDenied(issuer string) {
+	r.deniedTotal.WithLabelValues(issuer).Inc()
+}
+
+func (r *PrometheusRecorder) CRLEntries(issuer string, count int) {
+	r.entries.WithLabelValues(issuer).Set(float64(count))
+}
+
+func (r *PrometheusRecorder) CRLNextUpdate(issuer string, nextUpdate time.Time) {
+	r.nextUpdate.WithLabelValues(issuer).Set(float64(nextUpdate.Unix()))
+}
@@ -0,0 +1,164 @@
+package crlchecker
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+type ocspCacheKey struct {
+	issuer string
+	serial string
+}
+
+type ocspCacheEntry struct {
+	response *ocsp.Response
+	expiry   time.Time
+}
+
+// OCSPChecker consults stapled and live OCSP responses for a client
+// certificate, falling back to "unknown" when neither is conclusive so the
+// caller can fall back to a CRL check.
+type OCSPChecker struct {
+	client   *http.Client
+	hardFail bool
+
+	mu    sync.Mutex
+	cache map[ocspCacheKey]*ocspCacheEntry
+}
+
+// NewOCSPChecker creates an OCSPChecker. hardFail controls whether a
+// responder error blocks the request ("hard fail") or is reported as
+// ocsp.Unknown so the caller can fall back to CRL checking ("soft fail").
+func NewOCSPChecker(hardFail bool) *OCSPChecker {
+	return &OCSPChecker{
+		client:   &http.Client{Timeout: 10 * time.Second},
+		hardFail: hardFail,
+		cache:    make(map[ocspCacheKey]*ocspCacheEntry),
+	}
+}
+
+// Check returns the OCSP status for cert (ocsp.Good, ocsp.Revoked, or
+// ocsp.Unknown), consulting stapledResponse first if present, signed by
+// issuer, and not past its NextUpdate, then the in-memory cache, then the
+// certificate's AIA OCSP responder. issuer may be nil if the client did not
+// present its issuer's certificate; since a stapled response can't be
+// verified without it, no OCSP source is consulted and the result is
+// inconclusive.
+//
+// A non-nil error means hardFail is set and the status could not be
+// determined; callers must treat that as a denial rather than falling back
+// to a CRL check. When hardFail is false, an inconclusive result is reported
+// as (ocsp.Unknown, nil) instead, so the caller falls back to the CRL check.
+func (o *OCSPChecker) Check(cert, issuer *x509.Certificate, stapledResponse []byte) (int, error) {
+	if len(stapledResponse) > 0 && issuer != nil {
+		resp, err := ocsp.ParseResponseForCert(stapledResponse, cert, issuer)
+		switch {
+		case err != nil:
+			log.Printf("Ignoring invalid stapled OCSP response: %v", err)
+		case time.Now().After(resp.NextUpdate):
+			log.Printf("Ignoring stale stapled OCSP response (NextUpdate %s)", resp.NextUpdate)
+		default:
+			return resp.Status, nil
+		}
+	}
+
+	if issuer == nil {
+		return o.fail(fmt.Errorf("no issuer certificate available for OCSP check"))
+	}
+
+	if resp := o.cached(issuer, cert); resp != nil {
+		return resp.Status, nil
+	}
+
+	resp, err := o.query(cert, issuer)
+	if err != nil {
+		return o.fail(err)
+	}
+
+	o.store(issuer, cert, resp)
+	return resp.Status, nil
+}
+
+// fail reports an OCSP status that could not be determined. With hardFail
+// set, it returns a non-nil error so the caller denies the request; the
+// default soft-fail configuration instead logs the cause and reports
+// ocsp.Unknown so the caller falls back to the CRL check.
+func (o *OCSPChecker) fail(err error) (int, error) {
+	if o.hardFail {
+		return ocsp.Unknown, err
+	}
+	log.Printf("OCSP check unavailable, soft-failing to CRL check: %v", err)
+	return ocsp.Unknown, nil
+}
+
+func (o *OCSPChecker) query(cert, issuer *x509.Certificate) (*ocsp.Response, error) {
+	if len(cert.OCSPServer) == 0 {
+		return nil, fmt.Errorf("certificate has no OCSP responder URL")
+	}
+
+	reqBytes, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return nil, fmt.Errorf("building OCSP request: %w", err)
+	}
+
+	var lastErr error
+	for _, responderURL := range cert.OCSPServer {
+		resp, err := o.client.Post(responderURL, "application/ocsp-request", bytes.NewReader(reqBytes))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			lastErr = fmt.Errorf("OCSP responder %s returned status %s", responderURL, resp.Status)
+			continue
+		}
+
+		parsed, err := ocsp.ParseResponseForCert(body, cert, issuer)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return parsed, nil
+	}
+	return nil, fmt.Errorf("all OCSP responders failed: %w", lastErr)
+}
+
+func (o *OCSPChecker) cacheKey(issuer, cert *x509.Certificate) ocspCacheKey {
+	return ocspCacheKey{issuer: string(issuer.RawSubject), serial: cert.SerialNumber.String()}
+}
+
+func (o *OCSPChecker) cached(issuer, cert *x509.Certificate) *ocsp.Response {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	entry, ok := o.cache[o.cacheKey(issuer, cert)]
+	if !ok || time.Now().After(entry.expiry) {
+		return nil
+	}
+	return entry.response
+}
+
+func (o *OCSPChecker) store(issuer, cert *x509.Certificate, resp *ocsp.Response) {
+	if resp.NextUpdate.IsZero() {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.cache[o.cacheKey(issuer, cert)] = &ocspCacheEntry{response: resp, expiry: resp.NextUpdate}
+}
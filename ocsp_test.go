@@ -0,0 +1,104 @@
+package crlchecker
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// TestOCSPCheckerSoftFail verifies that with hardFail disabled (the
+// default), a Check that can't reach a conclusive answer reports
+// ocsp.Unknown with a nil error, so ServeHTTP falls back to the CRL check.
+func TestOCSPCheckerSoftFail(t *testing.T) {
+	ca := newTestCA(t, "ocsp-ca")
+	cert := ca.issueClientCert(t, "http://example.invalid/crl")
+
+	o := NewOCSPChecker(false)
+
+	// No issuer certificate and no stapled response: Check can't determine
+	// a status, so it must soft-fail rather than error.
+	status, err := o.Check(cert, nil, nil)
+	if err != nil {
+		t.Fatalf("expected soft-fail to return a nil error, got: %v", err)
+	}
+	if status != ocsp.Unknown {
+		t.Fatalf("expected ocsp.Unknown, got %d", status)
+	}
+}
+
+// TestOCSPCheckerHardFail is the counterpart: with hardFail enabled, the
+// same inconclusive check must return a non-nil error so the caller denies
+// the request instead of falling back to the CRL check.
+func TestOCSPCheckerHardFail(t *testing.T) {
+	ca := newTestCA(t, "ocsp-ca-2")
+	cert := ca.issueClientCert(t, "http://example.invalid/crl")
+
+	o := NewOCSPChecker(true)
+
+	status, err := o.Check(cert, nil, nil)
+	if err == nil {
+		t.Fatal("expected hard-fail to return a non-nil error")
+	}
+	if status != ocsp.Unknown {
+		t.Fatalf("expected ocsp.Unknown alongside the error, got %d", status)
+	}
+}
+
+// stapledResponse builds a DER-encoded OCSP response for cert, signed by ca,
+// with the given NextUpdate.
+func stapledResponse(t *testing.T, ca *testCA, cert *x509.Certificate, nextUpdate time.Time) []byte {
+	t.Helper()
+
+	resp, err := ocsp.CreateResponse(ca.cert, ca.cert, ocsp.Response{
+		Status:       ocsp.Good,
+		SerialNumber: cert.SerialNumber,
+		ThisUpdate:   time.Now().Add(-time.Hour),
+		NextUpdate:   nextUpdate,
+	}, ca.key)
+	if err != nil {
+		t.Fatalf("creating stapled OCSP response: %v", err)
+	}
+	return resp
+}
+
+// TestOCSPCheckerStapledResponseFreshAccepted verifies a stapled response
+// still within its NextUpdate window is honored directly, without
+// attempting a live query.
+func TestOCSPCheckerStapledResponseFreshAccepted(t *testing.T) {
+	ca := newTestCA(t, "ocsp-stapled-ca")
+	cert := ca.issueClientCert(t, "http://example.invalid/crl")
+
+	o := NewOCSPChecker(false)
+	resp := stapledResponse(t, ca, cert, time.Now().Add(time.Hour))
+
+	status, err := o.Check(cert, ca.cert, resp)
+	if err != nil {
+		t.Fatalf("expected a fresh stapled response to be accepted, got: %v", err)
+	}
+	if status != ocsp.Good {
+		t.Fatalf("expected ocsp.Good, got %d", status)
+	}
+}
+
+// TestOCSPCheckerStapledResponseStaleIgnored is the counterpart: a stapled
+// response past its NextUpdate must not be trusted, even though it parses
+// and verifies correctly otherwise. Since the test certificate has no AIA
+// OCSP responder to fall back to, Check soft-fails to ocsp.Unknown rather
+// than returning the stale response's (Good) status.
+func TestOCSPCheckerStapledResponseStaleIgnored(t *testing.T) {
+	ca := newTestCA(t, "ocsp-stapled-ca-2")
+	cert := ca.issueClientCert(t, "http://example.invalid/crl")
+
+	o := NewOCSPChecker(false)
+	resp := stapledResponse(t, ca, cert, time.Now().Add(-time.Minute))
+
+	status, err := o.Check(cert, ca.cert, resp)
+	if err != nil {
+		t.Fatalf("expected a stale stapled response to soft-fail rather than error, got: %v", err)
+	}
+	if status == ocsp.Good {
+		t.Fatal("expected a stale stapled response to be ignored, not trusted as ocsp.Good")
+	}
+}
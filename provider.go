@@ -0,0 +1,56 @@
+package crlchecker
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+)
+
+// CRLProvider supplies the revocation list that covers a given client
+// certificate. Implementations may read from disk, serve a fixed in-memory
+// set, or fetch over the network; CRLChecker treats them identically.
+type CRLProvider interface {
+	// CRL returns the revocation list covering cert's issuer, or an error
+	// if none is available.
+	CRL(cert *x509.Certificate) (*x509.RevocationList, error)
+	// Close releases any resources held by the provider.
+	Close() error
+}
+
+// MultiProvider queries a list of CRLProviders in order and returns the
+// first successful result. This lets operators compose sources, e.g. a
+// file provider backed by an HTTP fallback for issuers not shipped on
+// disk.
+type MultiProvider struct {
+	providers []CRLProvider
+}
+
+// NewMultiProvider creates a MultiProvider that tries providers in order.
+func NewMultiProvider(providers ...CRLProvider) *MultiProvider {
+	return &MultiProvider{providers: providers}
+}
+
+func (m *MultiProvider) CRL(cert *x509.Certificate) (*x509.RevocationList, error) {
+	var errs []error
+	for _, p := range m.providers {
+		crl, err := p.CRL(cert)
+		if err == nil {
+			return crl, nil
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("no CRL provider configured for issuer %q", cert.Issuer)
+	}
+	return nil, fmt.Errorf("no provider returned a CRL for issuer %q: %w", cert.Issuer, errors.Join(errs...))
+}
+
+func (m *MultiProvider) Close() error {
+	var errs []error
+	for _, p := range m.providers {
+		if err := p.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,293 @@
+package crlchecker
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce coalesces bursts of filesystem events (e.g. an editor's
+// write-to-temp-then-rename) into a single reload.
+const watchDebounce = 200 * time.Millisecond
+
+// FileCRLProvider loads one or more concatenated PEM-encoded CRLs from a
+// file on disk, or from every *.crl/*.pem file in a directory, indexes them
+// by issuer, and reloads whenever the underlying path changes.
+type FileCRLProvider struct {
+	path         string
+	validation   *crlValidation
+	pollFallback bool
+	recorder     Recorder
+	index        atomic.Value // map[string]*crlSet
+	done         chan struct{}
+}
+
+// NewFileCRLProvider creates a FileCRLProvider reading from path, performs
+// an initial load, and starts a background watcher that reloads whenever
+// the path changes. path may be a single CRL bundle file or a directory
+// containing *.crl/*.pem files, in which case files can be added or removed
+// from it over time. validation controls signature verification and
+// staleness grace for loaded CRLs; a nil validation trusts any well-formed
+// CRL as-is. pollFallback selects a 1-minute polling watcher instead of
+// fsnotify, for filesystems where inotify is unreliable (NFS, some
+// container overlays). recorder receives reload and CRL-entry metrics; a
+// nil recorder discards them.
+func NewFileCRLProvider(path string, validation *crlValidation, pollFallback bool, recorder Recorder) (*FileCRLProvider, error) {
+	if recorder == nil {
+		recorder = noopRecorder{}
+	}
+	p := &FileCRLProvider{
+		path:         path,
+		validation:   validation,
+		pollFallback: pollFallback,
+		recorder:     recorder,
+		done:         make(chan struct{}),
+	}
+	if err := p.reload(); err != nil {
+		return nil, err
+	}
+	// Started synchronously, not via goroutine: watchNotify's fsnotify.Add
+	// must be in place before we return, or a change made right after
+	// construction could land in the window before the watch exists and be
+	// silently missed. watchPoll has no such window: its first tick reloads
+	// unconditionally, since its last seen modification time starts at zero.
+	p.watch()
+	return p, nil
+}
+
+// readBundle reads the raw, concatenated PEM bytes for p.path: the file
+// itself, or every *.crl/*.pem file in it if it's a directory.
+func (p *FileCRLProvider) readBundle() ([]byte, error) {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat CRL path %s: %w", p.path, err)
+	}
+	if !info.IsDir() {
+		return os.ReadFile(p.path)
+	}
+
+	entries, err := os.ReadDir(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CRL directory %s: %w", p.path, err)
+	}
+
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		if entry.IsDir() || !isCRLFile(entry.Name()) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(p.path, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CRL file %s: %w", entry.Name(), err)
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+func isCRLFile(name string) bool {
+	return strings.HasSuffix(name, ".crl") || strings.HasSuffix(name, ".pem")
+}
+
+func (p *FileCRLProvider) reload() (err error) {
+	defer func() {
+		if err != nil {
+			p.recorder.CRLReload("error")
+		} else {
+			p.recorder.CRLReload("success")
+		}
+	}()
+
+	crlBytes, err := p.readBundle()
+	if err != nil {
+		return err
+	}
+
+	var crls []*x509.RevocationList
+	for {
+		block, rest := pem.Decode(crlBytes)
+		if block == nil {
+			break
+		}
+		parsed, err := x509.ParseRevocationList(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("failed to parse CRL at %s: %w", p.path, err)
+		}
+		crls = append(crls, parsed)
+		crlBytes = rest
+	}
+
+	sets := buildIssuerCRLSets(crls)
+	p.index.Store(sets)
+	recordCRLSetMetrics(p.recorder, sets)
+	log.Printf("CRL path %s loaded successfully (%d CRLs).", p.path, len(crls))
+	return nil
+}
+
+// watch establishes a watch on p.path, preferring fsnotify and falling back
+// to polling either by configuration or if the watcher couldn't be started
+// (e.g. the platform doesn't support inotify). It returns once the watch is
+// in place; the actual event handling runs in a background goroutine.
+func (p *FileCRLProvider) watch() {
+	if !p.pollFallback {
+		if err := p.watchNotify(); err == nil {
+			return
+		} else {
+			log.Printf("fsnotify unavailable for %s, falling back to polling: %v", p.path, err)
+		}
+	}
+	go p.watchPoll()
+}
+
+// watchNotify watches p.path (or, for a single file, its parent directory,
+// since editors commonly replace a file via a rename-into-place that an
+// inode-based watch on the file itself would miss) and reloads on
+// Write/Create/Rename/Remove events, debounced to coalesce bursts.
+func (p *FileCRLProvider) watchNotify() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("creating fsnotify watcher: %w", err)
+	}
+
+	watchPath := p.path
+	if info, err := os.Stat(p.path); err == nil && !info.IsDir() {
+		watchPath = filepath.Dir(p.path)
+	}
+	if err := watcher.Add(watchPath); err != nil {
+		watcher.Close()
+		return fmt.Errorf("watching %s: %w", watchPath, err)
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		var debounce *time.Timer
+		var debounceC <-chan time.Time
+		for {
+			select {
+			case <-p.done:
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !p.relevantEvent(event) {
+					continue
+				}
+				if debounce == nil {
+					debounce = time.NewTimer(watchDebounce)
+				} else {
+					debounce.Reset(watchDebounce)
+				}
+				debounceC = debounce.C
+			case <-debounceC:
+				debounceC = nil
+				if err := p.reload(); err != nil {
+					log.Printf("Failed to reload CRL from %s: %v", p.path, err)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("fsnotify error watching %s: %v", p.path, err)
+			}
+		}
+	}()
+	return nil
+}
+
+// relevantEvent reports whether event should trigger a reload: for a single
+// file, only events on that file; for a directory, Create/Write/Rename/
+// Remove of any *.crl/*.pem file within it.
+func (p *FileCRLProvider) relevantEvent(event fsnotify.Event) bool {
+	if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) == 0 {
+		return false
+	}
+
+	info, err := os.Stat(p.path)
+	if err != nil || !info.IsDir() {
+		return filepath.Clean(event.Name) == filepath.Clean(p.path)
+	}
+	return isCRLFile(filepath.Base(event.Name))
+}
+
+// pollInterval is how often watchPoll checks for changes. A var so tests
+// can shorten it rather than waiting out the real interval.
+var pollInterval = time.Minute
+
+// watchPoll reloads p once per pollInterval when its path's modification
+// time (or, for a directory, the latest modification time of any entry in
+// it) has advanced. Used when fsnotify is disabled or unavailable.
+func (p *FileCRLProvider) watchPoll() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var lastModTime time.Time
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			modTime, err := p.latestModTime()
+			if err != nil {
+				log.Printf("Error accessing CRL path %s: %v\n", p.path, err)
+				continue
+			}
+			if modTime.After(lastModTime) {
+				lastModTime = modTime
+				if err := p.reload(); err != nil {
+					log.Printf("Failed to reload CRL: %v\n", err)
+				}
+			}
+		}
+	}
+}
+
+func (p *FileCRLProvider) latestModTime() (time.Time, error) {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !info.IsDir() {
+		return info.ModTime(), nil
+	}
+
+	latest := info.ModTime()
+	entries, err := os.ReadDir(p.path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, entry := range entries {
+		entryInfo, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if entryInfo.ModTime().After(latest) {
+			latest = entryInfo.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// CRL returns the loaded CRL matching cert's issuer (by Authority Key
+// Identifier, falling back to raw issuer DN), applying p.validation and
+// rejecting it if expired beyond the configured staleness grace.
+func (p *FileCRLProvider) CRL(cert *x509.Certificate) (*x509.RevocationList, error) {
+	sets, _ := p.index.Load().(map[string]*crlSet)
+	return lookupCRL(sets, cert, p.validation)
+}
+
+func (p *FileCRLProvider) Close() error {
+	close(p.done)
+	return nil
+}
@@ -0,0 +1,156 @@
+package crlchecker
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCRLBundle writes a single CRL, revoking revokedSerials, as a PEM file
+// at path.
+func writeCRLBundle(t *testing.T, ca *testCA, path string, revokedSerials ...*big.Int) {
+	t.Helper()
+
+	now := time.Now()
+	var entries []x509.RevocationListEntry
+	for _, serial := range revokedSerials {
+		entries = append(entries, x509.RevocationListEntry{
+			SerialNumber:   serial,
+			RevocationTime: now.Add(-time.Minute),
+			ReasonCode:     1, // keyCompromise
+		})
+	}
+
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    big.NewInt(time.Now().UnixNano()),
+		ThisUpdate:                now.Add(-time.Hour),
+		NextUpdate:                now.Add(time.Hour),
+		RevokedCertificateEntries: entries,
+	}, ca.cert, ca.key)
+	if err != nil {
+		t.Fatalf("creating CRL: %v", err)
+	}
+
+	if err := os.WriteFile(path, encodeCRLPEM(t, der), 0o644); err != nil {
+		t.Fatalf("writing CRL file %s: %v", path, err)
+	}
+}
+
+// isRevoked reports whether provider reports cert as revoked, treating "no
+// CRL loaded for this issuer" as not revoked.
+func isRevoked(t *testing.T, provider *FileCRLProvider, cert *x509.Certificate) bool {
+	t.Helper()
+
+	crl, err := provider.CRL(cert)
+	if err != nil {
+		return false
+	}
+	for _, e := range crl.RevokedCertificateEntries {
+		if e.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// awaitCondition polls cond every 20ms until it returns true or timeout
+// elapses, failing the test if it never does.
+func awaitCondition(t *testing.T, timeout time.Duration, msg string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for: %s", msg)
+}
+
+// TestFileCRLProviderReloadsOnDirectoryChanges drives FileCRLProvider's real
+// fsnotify watcher against a temp directory, covering write, rename-into-
+// place, and removal of a *.crl file.
+func TestFileCRLProviderReloadsOnDirectoryChanges(t *testing.T) {
+	ca := newTestCA(t, "file-watch-ca")
+	cert := ca.issueClientCert(t, "http://example.invalid/crl")
+
+	dir := t.TempDir()
+	crlPath := filepath.Join(dir, "ca.crl")
+	writeCRLBundle(t, ca, crlPath)
+
+	provider, err := NewFileCRLProvider(dir, nil, false, nil)
+	if err != nil {
+		t.Fatalf("NewFileCRLProvider: %v", err)
+	}
+	defer provider.Close()
+
+	if isRevoked(t, provider, cert) {
+		t.Fatal("expected the certificate not to be revoked initially")
+	}
+
+	// Write: overwrite the file in place with a CRL that revokes cert.
+	writeCRLBundle(t, ca, crlPath, cert.SerialNumber)
+	awaitCondition(t, 3*time.Second, "reload after write to pick up the revocation", func() bool {
+		return isRevoked(t, provider, cert)
+	})
+
+	// Rename-into-place: write a replacement file elsewhere, then rename it
+	// over crlPath, as editors commonly do, reverting to not-revoked.
+	tmpPath := filepath.Join(dir, "ca.crl.tmp")
+	writeCRLBundle(t, ca, tmpPath)
+	if err := os.Rename(tmpPath, crlPath); err != nil {
+		t.Fatalf("renaming %s to %s: %v", tmpPath, crlPath, err)
+	}
+	awaitCondition(t, 3*time.Second, "reload after rename-into-place to clear the revocation", func() bool {
+		return !isRevoked(t, provider, cert)
+	})
+
+	// Remove: deleting the file should drop the issuer from the index.
+	if err := os.Remove(crlPath); err != nil {
+		t.Fatalf("removing %s: %v", crlPath, err)
+	}
+	awaitCondition(t, 3*time.Second, "reload after removal to drop the issuer's CRL", func() bool {
+		_, err := provider.CRL(cert)
+		return err != nil
+	})
+}
+
+// TestFileCRLProviderPollFallbackReloadsOnChange covers the pollFallback
+// path: a change to the watched file is picked up once its modification
+// time advances, without relying on fsnotify.
+func TestFileCRLProviderPollFallbackReloadsOnChange(t *testing.T) {
+	oldInterval := pollInterval
+	pollInterval = 50 * time.Millisecond
+	defer func() { pollInterval = oldInterval }()
+
+	ca := newTestCA(t, "file-poll-ca")
+	cert := ca.issueClientCert(t, "http://example.invalid/crl")
+
+	dir := t.TempDir()
+	crlPath := filepath.Join(dir, "ca.crl")
+	writeCRLBundle(t, ca, crlPath)
+
+	provider, err := NewFileCRLProvider(crlPath, nil, true, nil)
+	if err != nil {
+		t.Fatalf("NewFileCRLProvider: %v", err)
+	}
+	defer provider.Close()
+
+	if isRevoked(t, provider, cert) {
+		t.Fatal("expected the certificate not to be revoked initially")
+	}
+
+	// Ensure the rewritten file's mtime is observably later than the
+	// initial write, since watchPoll only reloads when it advances.
+	time.Sleep(10 * time.Millisecond)
+	writeCRLBundle(t, ca, crlPath, cert.SerialNumber)
+
+	awaitCondition(t, 3*time.Second, "poll reload after mtime advances to pick up the revocation", func() bool {
+		return isRevoked(t, provider, cert)
+	})
+}
@@ -0,0 +1,165 @@
+package crlchecker
+
+import (
+	"container/list"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultHTTPCacheSize is the number of issuers' CRLs the HTTPCRLProvider
+// keeps cached in memory at once.
+const DefaultHTTPCacheSize = 128
+
+// HTTPCRLProvider fetches CRLs from the CRL Distribution Point (RFC 5280
+// §4.2.1.13) listed on the client certificate and caches the result in
+// memory, keyed by issuer, until the CRL's NextUpdate expires.
+type HTTPCRLProvider struct {
+	client     *http.Client
+	cacheSize  int
+	validation *crlValidation
+	recorder   Recorder
+
+	mu    sync.Mutex
+	cache map[string]*list.Element // issuer -> LRU element
+	order *list.List
+}
+
+type httpCacheEntry struct {
+	issuer string
+	crl    *x509.RevocationList
+	expiry time.Time
+}
+
+// NewHTTPCRLProvider creates an HTTPCRLProvider using client to fetch CRLs,
+// keeping at most cacheSize issuers' CRLs cached. A nil client or
+// non-positive cacheSize fall back to sensible defaults. validation
+// controls signature verification and staleness grace for fetched CRLs; a
+// nil validation trusts any well-formed CRL as-is. recorder receives
+// fetch and CRL-entry metrics; a nil recorder discards them.
+func NewHTTPCRLProvider(client *http.Client, cacheSize int, validation *crlValidation, recorder Recorder) *HTTPCRLProvider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if cacheSize <= 0 {
+		cacheSize = DefaultHTTPCacheSize
+	}
+	if recorder == nil {
+		recorder = noopRecorder{}
+	}
+	return &HTTPCRLProvider{
+		client:     client,
+		cacheSize:  cacheSize,
+		validation: validation,
+		recorder:   recorder,
+		cache:      make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+func (p *HTTPCRLProvider) CRL(cert *x509.Certificate) (*x509.RevocationList, error) {
+	issuer := string(cert.RawIssuer)
+
+	if crl := p.fromCache(issuer); crl != nil {
+		return crl, nil
+	}
+
+	if len(cert.CRLDistributionPoints) == 0 {
+		return nil, fmt.Errorf("certificate has no CRL distribution points")
+	}
+
+	var lastErr error
+	for _, cdp := range cert.CRLDistributionPoints {
+		crl, err := p.fetch(cdp)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if err := verifyCRL(crl, p.validation, time.Now()); err != nil {
+			lastErr = err
+			continue
+		}
+		if crlIssuerKey(crl) != issuerKey(cert.AuthorityKeyId, cert.RawIssuer) {
+			lastErr = fmt.Errorf("CRL fetched from %s covers issuer %q, not certificate issuer %q", cdp, crl.Issuer, cert.Issuer)
+			continue
+		}
+		p.store(issuer, crl)
+		p.recorder.CRLReload("success")
+		p.recorder.CRLEntries(crl.Issuer.String(), len(crl.RevokedCertificateEntries))
+		p.recorder.CRLNextUpdate(crl.Issuer.String(), crl.NextUpdate)
+		return crl, nil
+	}
+	p.recorder.CRLReload("error")
+	return nil, fmt.Errorf("failed to fetch CRL from distribution points for issuer %q: %w", cert.Issuer, lastErr)
+}
+
+func (p *HTTPCRLProvider) fromCache(issuer string) *x509.RevocationList {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	elem, ok := p.cache[issuer]
+	if !ok {
+		return nil
+	}
+	entry := elem.Value.(*httpCacheEntry)
+	if time.Now().After(entry.expiry) {
+		p.order.Remove(elem)
+		delete(p.cache, issuer)
+		return nil
+	}
+	p.order.MoveToFront(elem)
+	return entry.crl
+}
+
+func (p *HTTPCRLProvider) fetch(url string) (*x509.RevocationList, error) {
+	resp, err := p.client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("fetching CRL from %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching CRL from %s: unexpected status %s", url, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading CRL response from %s: %w", url, err)
+	}
+
+	crl, err := x509.ParseRevocationList(body)
+	if err != nil {
+		return nil, fmt.Errorf("parsing CRL from %s: %w", url, err)
+	}
+	return crl, nil
+}
+
+func (p *HTTPCRLProvider) store(issuer string, crl *x509.RevocationList) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry := &httpCacheEntry{issuer: issuer, crl: crl, expiry: crl.NextUpdate}
+	if elem, ok := p.cache[issuer]; ok {
+		elem.Value = entry
+		p.order.MoveToFront(elem)
+		return
+	}
+
+	p.cache[issuer] = p.order.PushFront(entry)
+
+	for p.order.Len() > p.cacheSize {
+		oldest := p.order.Back()
+		if oldest == nil {
+			break
+		}
+		p.order.Remove(oldest)
+		delete(p.cache, oldest.Value.(*httpCacheEntry).issuer)
+	}
+}
+
+func (p *HTTPCRLProvider) Close() error {
+	return nil
+}
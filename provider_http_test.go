@@ -0,0 +1,142 @@
+package crlchecker
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testCA is a minimal self-signed CA used to issue a client certificate and
+// sign CRLs in tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, cn string) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating CA key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		SubjectKeyId:          []byte(cn),
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("creating CA certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parsing CA certificate: %v", err)
+	}
+	return &testCA{cert: cert, key: key}
+}
+
+// issueClientCert issues a certificate signed by ca with the given CRL
+// distribution point URL.
+func (ca *testCA) issueClientCert(t *testing.T, cdpURL string) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating client key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "test-client"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		AuthorityKeyId:        ca.cert.SubjectKeyId,
+		CRLDistributionPoints: []string{cdpURL},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("creating client certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parsing client certificate: %v", err)
+	}
+	return cert
+}
+
+// certPEM PEM-encodes cert.
+func certPEM(t *testing.T, cert *x509.Certificate) string {
+	t.Helper()
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+}
+
+// crl returns a DER-encoded, empty CRL signed by ca.
+func (ca *testCA) crl(t *testing.T) []byte {
+	t.Helper()
+
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now().Add(-time.Minute),
+		NextUpdate: time.Now().Add(time.Hour),
+	}, ca.cert, ca.key)
+	if err != nil {
+		t.Fatalf("creating CRL: %v", err)
+	}
+	return der
+}
+
+// TestHTTPCRLProviderRejectsWrongIssuerCRL guards against a CDP response
+// (e.g. from a misconfigured responder, or a CDP URL embedded by the
+// presenting certificate itself) supplying a validly-signed CRL for a
+// different issuer than the one being checked.
+func TestHTTPCRLProviderRejectsWrongIssuerCRL(t *testing.T) {
+	legitCA := newTestCA(t, "legit-ca")
+	otherCA := newTestCA(t, "other-ca")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(otherCA.crl(t))
+	}))
+	defer server.Close()
+
+	clientCert := legitCA.issueClientCert(t, server.URL)
+
+	provider := NewHTTPCRLProvider(nil, 0, nil, nil)
+	defer provider.Close()
+
+	if _, err := provider.CRL(clientCert); err == nil {
+		t.Fatal("expected an error for a CRL signed by a different issuer, got nil")
+	}
+}
+
+// TestHTTPCRLProviderAcceptsMatchingIssuerCRL is the positive counterpart:
+// a CRL from the cert's actual issuer is accepted.
+func TestHTTPCRLProviderAcceptsMatchingIssuerCRL(t *testing.T) {
+	legitCA := newTestCA(t, "legit-ca")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(legitCA.crl(t))
+	}))
+	defer server.Close()
+
+	clientCert := legitCA.issueClientCert(t, server.URL)
+
+	provider := NewHTTPCRLProvider(nil, 0, nil, nil)
+	defer provider.Close()
+
+	if _, err := provider.CRL(clientCert); err != nil {
+		t.Fatalf("expected the matching-issuer CRL to be accepted, got: %v", err)
+	}
+}
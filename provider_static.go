@@ -0,0 +1,55 @@
+package crlchecker
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+)
+
+// StaticCRLProvider serves a fixed, issuer-indexed set of CRLs parsed once
+// from raw PEM bytes supplied at construction time. It never reloads,
+// which makes it useful for tests and for CRLs embedded directly in
+// plugin configuration.
+type StaticCRLProvider struct {
+	index      map[string]*crlSet
+	validation *crlValidation
+}
+
+// NewStaticCRLProvider parses pemBytes, a sequence of concatenated
+// PEM-encoded CRLs, into a StaticCRLProvider. validation controls
+// signature verification and staleness grace for loaded CRLs; a nil
+// validation trusts any well-formed CRL as-is. recorder receives load and
+// CRL-entry metrics; a nil recorder discards them.
+func NewStaticCRLProvider(pemBytes []byte, validation *crlValidation, recorder Recorder) (*StaticCRLProvider, error) {
+	if recorder == nil {
+		recorder = noopRecorder{}
+	}
+
+	var crls []*x509.RevocationList
+	for {
+		block, rest := pem.Decode(pemBytes)
+		if block == nil {
+			break
+		}
+		parsed, err := x509.ParseRevocationList(block.Bytes)
+		if err != nil {
+			recorder.CRLReload("error")
+			return nil, fmt.Errorf("failed to parse static CRL: %w", err)
+		}
+		crls = append(crls, parsed)
+		pemBytes = rest
+	}
+
+	index := buildIssuerCRLSets(crls)
+	recorder.CRLReload("success")
+	recordCRLSetMetrics(recorder, index)
+	return &StaticCRLProvider{index: index, validation: validation}, nil
+}
+
+func (p *StaticCRLProvider) CRL(cert *x509.Certificate) (*x509.RevocationList, error) {
+	return lookupCRL(p.index, cert, p.validation)
+}
+
+func (p *StaticCRLProvider) Close() error {
+	return nil
+}